@@ -9,11 +9,11 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path"
 	"strings"
 	"sync"
@@ -21,16 +21,22 @@ import (
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"golang.org/x/sync/semaphore"
 
 	"github.com/bigredeye/notmanytask/api"
+	"github.com/bigredeye/notmanytask/internal/config"
+	"github.com/bigredeye/notmanytask/internal/metrics"
+	"github.com/bigredeye/notmanytask/internal/runtime"
+	"github.com/bigredeye/notmanytask/internal/submits"
 )
 
 func isRegularFile(path string) bool {
 	if stat, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
 		return false
 	} else if err != nil {
-		log.Printf("Failed to stat file %s: %+v", path, err)
+		log.Error().Err(err).Str("path", path).Msg("Failed to stat file")
 		return false
 	} else {
 		return stat.Mode().IsRegular()
@@ -41,7 +47,7 @@ func isDirectory(path string) bool {
 	if stat, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
 		return false
 	} else if err != nil {
-		log.Printf("Failed to stat file %s: %+v", path, err)
+		log.Error().Err(err).Str("path", path).Msg("Failed to stat file")
 		return false
 	} else {
 		return stat.Mode().IsDir()
@@ -49,8 +55,9 @@ func isDirectory(path string) bool {
 }
 
 type flagFetcher struct {
-	url   string
-	token string
+	url     string
+	token   string
+	metrics *metrics.Checker
 }
 
 func (f flagFetcher) doFetchFlag(task string) (string, error) {
@@ -63,24 +70,24 @@ func (f flagFetcher) doFetchFlag(task string) (string, error) {
 	}
 	res, err := http.Post(f.url, "application/json", bytes.NewReader(buf))
 	if err != nil {
-		log.Printf("Failed to send flag request: %+v\n", err)
+		log.Error().Err(err).Str("task", task).Msg("Failed to send flag request")
 		return "", err
 	}
 
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		log.Printf("Failed to read response body: %+v\n", err)
+		log.Error().Err(err).Str("task", task).Msg("Failed to read response body")
 		return "", err
 	}
 
 	response := api.FlagResponse{}
 	err = json.Unmarshal(body, &response)
 	if err != nil {
-		log.Printf("Failed to parse response json: %+v\n", err)
+		log.Error().Err(err).Str("task", task).Msg("Failed to parse response json")
 		return "", err
 	}
 	if !response.Ok {
-		log.Printf("Flag request failed: %s\n", response.Error)
+		log.Error().Str("task", task).Str("reason", response.Error).Msg("Flag request failed")
 		return "", fmt.Errorf("server error: %s", response.Error)
 	}
 	return response.Flag, nil
@@ -89,12 +96,20 @@ func (f flagFetcher) doFetchFlag(task string) (string, error) {
 func (f flagFetcher) fetchFlag(task string) (string, error) {
 	flag := ""
 
+	attempt := 0
 	backoffPolicy := backoff.NewExponentialBackOff()
 	backoffPolicy.MaxElapsedTime = time.Second * 15
 	err := backoff.Retry(func() error {
+		if attempt > 0 && f.metrics != nil {
+			f.metrics.FlagFetchRetries.Inc()
+		}
+		attempt++
+
 		var err error
 		flag, err = f.doFetchFlag(task)
-		log.Printf("Failed to fetch flag: %+v\n", err)
+		if err != nil {
+			log.Warn().Err(err).Str("task", task).Int("attempt", attempt).Msg("Failed to fetch flag, retrying")
+		}
 		return err
 	}, backoffPolicy)
 
@@ -102,98 +117,166 @@ func (f flagFetcher) fetchFlag(task string) (string, error) {
 }
 
 func main() {
+	zerolog.TimeFieldFormat = time.RFC3339
+
 	listenAddress := flag.String("address", ":3333", "Address to listen on")
+	metricsAddress := flag.String("metrics-address", ":9090", "Address to serve Prometheus metrics on")
 	binariesDirectory := flag.String("build", "", "Path to build directory")
 	submitsDirectory := flag.String("submits", "", "Path to directory to store submits")
 	concurrencyLevel := flag.Int64("concurrency", 16, "Max number of computation-heavy tasks to run")
+	runtimeBackend := flag.String("runtime", "bare", "Sandbox backend to run submitted binaries with: bare, bwrap or runc")
+	configPath := flag.String("config", "", "Path to a YAML config with per-task limit/scoring overrides; reloaded on SIGHUP")
+	drainTimeout := flag.Duration("drain-timeout", 90*time.Second, "How long to let in-flight connections finish after a shutdown signal before cancelling them")
 	flag.Parse()
 
-	checker, err := newChecker(*binariesDirectory, *submitsDirectory, *concurrencyLevel, os.Getenv("CRASHME_URL"), os.Getenv("CRASHME_TOKEN"))
+	checker, err := newChecker(*binariesDirectory, *submitsDirectory, *concurrencyLevel, *runtimeBackend, *configPath, os.Getenv("CRASHME_URL"), os.Getenv("CRASHME_TOKEN"))
 	if err != nil {
 		panic(err)
 	}
 
+	go func() {
+		if err := metrics.Serve(*metricsAddress); err != nil {
+			log.Error().Err(err).Msg("Metrics server stopped")
+		}
+	}()
+
 	listener, err := net.Listen("tcp", *listenAddress)
 	if err != nil {
 		panic(err)
 	}
-	defer listener.Close()
+
+	rootCtx, cancelRoot := context.WithCancel(context.Background())
+	defer cancelRoot()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
+	go func() {
+		sig := <-sigCh
+		log.Info().Stringer("signal", sig).Msg("Received shutdown signal, draining connections")
+		checker.metrics.Up.Set(0)
+		listener.Close()
+		time.AfterFunc(*drainTimeout, func() {
+			log.Warn().Dur("drainTimeout", *drainTimeout).Msg("Drain timeout elapsed, cancelling in-flight connections")
+			cancelRoot()
+		})
+	}()
+
+	checker.metrics.Up.Set(1)
 
 	acceptErrorsBudget := 10
 	currentAcceptErrorsBudget := acceptErrorsBudget
+	checker.metrics.AcceptErrorsBudget.Set(float64(acceptErrorsBudget))
 	connId := 0
+	var wg sync.WaitGroup
 
+acceptLoop:
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
-			log.Printf("Failed to accept: %+v", err)
+			if errors.Is(err, net.ErrClosed) {
+				break acceptLoop
+			}
+			log.Error().Err(err).Msg("Failed to accept")
+			checker.metrics.AcceptErrors.Inc()
 			if currentAcceptErrorsBudget == 0 {
+				checker.metrics.Up.Set(0)
 				panic(err)
 			}
 			currentAcceptErrorsBudget--
 		} else if currentAcceptErrorsBudget < acceptErrorsBudget {
 			currentAcceptErrorsBudget++
 		}
-
-		go checker.handleConnection(context.Background(), conn, connId)
+		checker.metrics.AcceptErrorsBudget.Set(float64(currentAcceptErrorsBudget))
+		if err != nil {
+			continue
+		}
+		checker.metrics.AcceptedConns.Inc()
+
+		wg.Add(1)
+		connID := connId
+		go func(conn net.Conn) {
+			defer wg.Done()
+			checker.handleConnection(rootCtx, conn, connID)
+		}(conn)
 		connId++
 	}
-}
 
-// FIXME(BigRedEye): Read from config
-const MAX_INPUT_SIZE = 10 * 1024 * 1024 // 10MiB
-const MAX_FIRST_LINE_SIZE = 100
+	wg.Wait()
+	log.Info().Msg("All connections drained, exiting")
+}
 
 type checker struct {
 	binariesDirectory string
-	submitsDirectory  string
+	archive           *submits.Archive
 	sema              *semaphore.Weighted
 	flagFetcher       flagFetcher
+	runtime           runtime.Backend
+	config            *config.Watcher
+	metrics           *metrics.Checker
 }
 
-func newChecker(binariesDirectory, submitsDirectory string, concurrencyLevel int64, url, token string) (*checker, error) {
-	err := os.MkdirAll(submitsDirectory, 0755)
+func newChecker(binariesDirectory, submitsDirectory string, concurrencyLevel int64, runtimeBackend, configPath, url, token string) (*checker, error) {
+	archive, err := submits.NewArchive(submitsDirectory)
 	if err != nil {
-		return nil, fmt.Errorf("failed to mkdir submits directory: %w", err)
+		return nil, fmt.Errorf("failed to open submits archive: %w", err)
 	}
 
 	if !isDirectory(binariesDirectory) {
 		return nil, fmt.Errorf("binaries directory does not exist")
 	}
 
+	backend, err := runtime.New(runtimeBackend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up runtime backend: %w", err)
+	}
+
+	watcher, err := config.NewWatcher(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	checkerMetrics := metrics.NewChecker()
+
 	return &checker{
 		binariesDirectory: binariesDirectory,
-		submitsDirectory:  submitsDirectory,
+		archive:           archive,
 		sema:              semaphore.NewWeighted(concurrencyLevel),
 		flagFetcher: flagFetcher{
-			token: token,
-			url:   url,
+			token:   token,
+			url:     url,
+			metrics: checkerMetrics,
 		},
+		runtime: backend,
+		config:  watcher,
+		metrics: checkerMetrics,
 	}, nil
 }
 
 func (c *checker) handleConnection(ctx context.Context, conn net.Conn, connID int) {
+	started := time.Now()
+	logger := log.With().Int("connId", connID).Str("remote", conn.RemoteAddr().String()).Logger()
+
 	defer func() {
 		conn.Close()
-		log.Printf("Closed connection #%d from %s\n", connID, conn.RemoteAddr())
+		logger.Info().Dur("duration", time.Since(started)).Msg("Closed connection")
 	}()
-	log.Printf("New connection from #%d from %s\n", connID, conn.RemoteAddr())
+	logger.Info().Msg("New connection")
 
-	err := c.doHandleConnection(ctx, conn)
+	err := c.doHandleConnection(ctx, conn, connID, &logger)
 	if err != nil {
-		log.Printf("Failed to handle connection: %+v", err)
+		logger.Error().Err(err).Msg("Failed to handle connection")
 		io.WriteString(conn, fmt.Sprintf("Error: %s\n", err.Error()))
 	}
 }
 
-func slowReadFirstLine(reader io.Reader) (string, error) {
+func slowReadFirstLine(reader io.Reader, maxFirstLineSize int) (string, error) {
 	var str strings.Builder
 	buf := []byte{' '}
 	for buf[0] != '\n' {
 		n, err := reader.Read(buf)
 
 		if err == io.EOF || (err == nil && n != 1) {
-			if str.Len() == MAX_FIRST_LINE_SIZE {
+			if str.Len() == maxFirstLineSize {
 				return "", fmt.Errorf("too long first line")
 			}
 			return "", fmt.Errorf("EOF before new line")
@@ -207,26 +290,39 @@ func slowReadFirstLine(reader io.Reader) (string, error) {
 	return strings.TrimSpace(str.String()), nil
 }
 
-func (c *checker) doHandleConnection(ctx context.Context, conn net.Conn) error {
-	ctx, cancel := context.WithTimeout(ctx, time.Minute) // FIXME(BigRedEye): Timeout from config
+func (c *checker) doHandleConnection(parentCtx context.Context, conn net.Conn, connID int, logger *zerolog.Logger) error {
+	cfg := c.config.Current()
+	globalLimits := cfg.Limits
+
+	ctx, cancel := context.WithTimeout(parentCtx, globalLimits.Timeout)
 	defer cancel()
 
 	if !c.sema.TryAcquire(1) {
 		io.WriteString(conn, "Waiting for an available runner...\n")
+		waitStarted := time.Now()
 		err := c.sema.Acquire(ctx, 1)
+		c.metrics.SemaphoreWait.Observe(time.Since(waitStarted).Seconds())
 		if err != nil {
 			return fmt.Errorf("failed to acquire semaphore: %w", err)
 		}
+	} else {
+		c.metrics.SemaphoreWait.Observe(0)
 	}
-	defer c.sema.Release(1)
+	c.metrics.ConcurrentRunners.Inc()
+	defer func() {
+		c.metrics.ConcurrentRunners.Dec()
+		c.sema.Release(1)
+	}()
 
 	io.WriteString(conn, "Enter task name: ")
-	reader := io.LimitReader(conn, MAX_INPUT_SIZE)
+	bytesIn := &countingReader{r: io.LimitReader(conn, globalLimits.MaxInputSize)}
+	var reader io.Reader = bytesIn
 	// User should pass task name in the first line
-	task, err := slowReadFirstLine(io.LimitReader(reader, MAX_FIRST_LINE_SIZE))
+	task, err := slowReadFirstLine(io.LimitReader(reader, int64(globalLimits.MaxFirstLineSize)), globalLimits.MaxFirstLineSize)
 	if err != nil {
 		return fmt.Errorf("failed to read first line: %w", err)
 	}
+	*logger = logger.With().Str("task", task).Logger()
 
 	task = strings.ReplaceAll(task, "_", "-")
 	executablePath := path.Join(c.binariesDirectory, "ctf_"+strings.ReplaceAll(task, "-", "_"))
@@ -234,32 +330,114 @@ func (c *checker) doHandleConnection(ctx context.Context, conn net.Conn) error {
 		return fmt.Errorf("unknown task %s (@ %s)", task, executablePath)
 	}
 
-	inputPath := path.Join(c.submitsDirectory, task+"_"+time.Now().Format("2006-01-02T15:04:05.000"))
-	submitFile, err := os.Create(inputPath)
+	limits := cfg.LimitsForTask(task)
+	if limits.Timeout != globalLimits.Timeout {
+		// The task asked for a different wall-clock budget than the global
+		// default: re-derive the deadline from parentCtx (not the one we
+		// just created), so a longer per-task timeout isn't clamped by the
+		// global one.
+		cancel()
+		ctx, cancel = context.WithTimeout(parentCtx, limits.Timeout)
+		defer cancel()
+	}
+
+	pendingSubmit, err := c.archive.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to create input file: %w", err)
+		return fmt.Errorf("failed to archive submit: %w", err)
 	}
-	reader = io.TeeReader(reader, submitFile)
+	reader = io.TeeReader(reader, pendingSubmit.Writer())
 
 	stderr := bytes.Buffer{}
-	cmd := exec.Command(executablePath)
-	proxy, err := newCommandProxy(reader, conn, &stderr, cmd)
+	var (
+		outcome      = "error"
+		exitCodeMeta = -1
+		flagMeta     string
+		taskDuration time.Duration
+	)
+	defer func() {
+		meta := submits.Meta{
+			Task:           task,
+			ConnID:         connID,
+			Outcome:        outcome,
+			ExitCode:       exitCodeMeta,
+			WallTime:       taskDuration,
+			StderrTail:     tailOf(stderr.String(), 4096),
+			Flag:           flagMeta,
+			RemoteAddrHash: submits.HashString(conn.RemoteAddr().String()),
+			ConfigHash:     cfg.Hash(),
+			CreatedAt:      time.Now(),
+		}
+		if _, err := pendingSubmit.Finish(meta); err != nil {
+			logger.Error().Err(err).Msg("Failed to archive submit")
+		}
+	}()
+
+	spec := runtime.Spec{
+		Executable:     executablePath,
+		Chroot:         c.binariesDirectory,
+		BindMounts:     map[string]string{pendingSubmit.Path(): pendingSubmit.Path()},
+		ReadOnlyRootfs: true,
+		PreCommand:     cfg.PreCommandForTask(task),
+		Limits: runtime.Limits{
+			CPUTime:      limits.CPUTime,
+			AddressSpace: limits.AddressSpace,
+			MaxPIDs:      64,
+			Network:      false,
+		},
+	}
+
+	cmd, cleanup, err := c.runtime.BuildCommand(ctx, spec)
+	if err != nil {
+		return fmt.Errorf("failed to build sandboxed command: %w", err)
+	}
+	defer cleanup()
+	// On ctx cancellation (task timeout or a drain-deadline shutdown),
+	// ask the child to exit cleanly before resorting to SIGKILL.
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = 5 * time.Second
+
+	bytesOut := &countingWriter{w: conn}
+	proxy, err := newCommandProxy(reader, bytesOut, &stderr, cmd)
 	if err != nil {
 		return fmt.Errorf("failed to prepare command: %w", err)
 	}
 
 	io.WriteString(conn, fmt.Sprintf("Running task %s\n", task))
+	taskStarted := time.Now()
 	err = proxy.run()
+	taskDuration = time.Since(taskStarted)
+	c.metrics.TaskDuration.WithLabelValues(task).Observe(taskDuration.Seconds())
+
+	reason, exitCode := runtime.Classify(ctx, err)
+	exitCodeMeta = exitCode
+	finish := logger.Info().Dur("taskDuration", taskDuration).Int64("bytesIn", bytesIn.n).Int64("bytesOut", bytesOut.n)
+
+	switch reason {
+	case runtime.ExitTimeout:
+		outcome = "timeout"
+		c.metrics.TaskResults.WithLabelValues(task, outcome).Inc()
+		finish.Str("outcome", outcome).Msg("Command hit the wall clock timeout")
+		return fmt.Errorf("command timed out")
+	case runtime.ExitOOM:
+		outcome = "oom"
+		c.metrics.TaskResults.WithLabelValues(task, outcome).Inc()
+		finish.Str("outcome", outcome).Msg("Command was killed, likely OOM")
+		return fmt.Errorf("command exceeded its memory limit")
+	case runtime.ExitSignaled:
+		outcome = "signaled"
+		c.metrics.TaskResults.WithLabelValues(task, outcome).Inc()
+		finish.Str("outcome", outcome).Msg("Command was interrupted")
+		return fmt.Errorf("got EOF before command exit")
+	}
 
 	if err != nil {
 		var exitError *exec.ExitError
 		if errors.As(err, &exitError) {
-			log.Printf("Command %s failed with code %d, status: %s", task, exitError.ExitCode(), exitError.String())
-			status := exitError.Sys().(syscall.WaitStatus)
-			if status.Signal() == os.Interrupt {
-				log.Printf("Command was interrupted")
-				return fmt.Errorf("got EOF before command exit")
-			}
+			outcome = "failed"
+			c.metrics.TaskResults.WithLabelValues(task, outcome).Inc()
+			finish.Int("exitCode", exitCode).Str("outcome", outcome).Msg("Command failed, trying to fetch flag")
 
 			_, err = io.WriteString(conn, fmt.Sprintf("Command failed: %s\nTrying to fetch flag...\n", exitError.ProcessState))
 			if err != nil {
@@ -268,22 +446,60 @@ func (c *checker) doHandleConnection(ctx context.Context, conn net.Conn) error {
 
 			flag, err := c.flagFetcher.fetchFlag(task)
 			if err != nil {
-				log.Printf("Failed to fetch flag for failed task: %+v\n", err)
+				logger.Error().Err(err).Msg("Failed to fetch flag for failed task")
 				return fmt.Errorf("failed to fetch flag, try again a few minutes later")
 			}
+			flagMeta = flag
 
 			io.WriteString(conn, flag+"\n")
 			return nil
-		} else {
-			log.Printf("Failed to run command %s: %s", executablePath, stderr)
-			return fmt.Errorf("failed to start command: %w, stderr: %s", err, stderr)
 		}
+
+		finish.Discard()
+		outcome = "error"
+		c.metrics.TaskResults.WithLabelValues(task, outcome).Inc()
+		logger.Error().Err(err).Str("stderr", stderr.String()).Msg("Failed to run command")
+		return fmt.Errorf("failed to start command: %w, stderr: %s", err, stderr)
 	}
 
+	outcome = "success"
+	c.metrics.TaskResults.WithLabelValues(task, outcome).Inc()
+	finish.Str("outcome", outcome).Msg("Command finished normally")
 	io.WriteString(conn, "Command finished normally\n")
 	return nil
 }
 
+// tailOf returns the last n bytes of s, useful for storing a bounded stderr
+// snippet in a submit's meta.json instead of an unbounded blob.
+func tailOf(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
 type commandProxy struct {
 	stdin  io.Reader
 	stdout io.Writer
@@ -304,7 +520,7 @@ func newCommandProxy(stdin io.Reader, stdout io.Writer, stderr io.Writer, cmd *e
 		cmd:    cmd,
 		wg:     &sync.WaitGroup{},
 	}
-	proxy.wg.Add(2)
+	proxy.wg.Add(3)
 
 	var err error
 	proxy.stdoutPipe, err = cmd.StdoutPipe()
@@ -334,11 +550,17 @@ func (c *commandProxy) run() error {
 	go c.handleStderr()
 
 	err = c.cmd.Wait()
+	// handleStdin copies from the connection, which may have nothing left
+	// to read even after the command exits; closing the pipe here unblocks
+	// its write side instead of leaking the goroutine until the connection
+	// itself closes.
+	c.stdinPipe.Close()
 	c.wg.Wait()
 	return err
 }
 
 func (c *commandProxy) handleStdin() {
+	defer c.wg.Done()
 	io.Copy(c.stdinPipe, c.stdin)
 
 	// in case of closed connection
@@ -350,20 +572,16 @@ func (c *commandProxy) handleStdin() {
 	// It is kind of racy, but who cares anyway
 	err := c.cmd.Process.Signal(os.Interrupt)
 	if err == nil {
-		log.Printf("Sent SIGINT to the child process (connection is closed?)")
+		log.Debug().Msg("Sent SIGINT to the child process (connection is closed?)")
 	}
-
-	log.Printf("Done stdin")
 }
 
 func (c *commandProxy) handleStdout() {
 	copyAndDone(c.stdout, c.stdoutPipe, c.wg)
-	log.Printf("Done stdout")
 }
 
 func (c *commandProxy) handleStderr() {
 	copyAndDone(c.stderr, c.stderrPipe, c.wg)
-	log.Printf("Done stderr")
 }
 
 func copyAndDone(dst io.Writer, src io.Reader, wg *sync.WaitGroup) {
@@ -0,0 +1,184 @@
+// Command replay re-runs a submit stored by the checker's submits archive
+// through the same sandboxed runtime.Backend, and prints how the replayed
+// run differs from what was recorded at the time. It's meant for course
+// staff debugging why a student's submit did or didn't get a flag.
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bigredeye/notmanytask/internal/config"
+	"github.com/bigredeye/notmanytask/internal/runtime"
+	"github.com/bigredeye/notmanytask/internal/submits"
+)
+
+func main() {
+	stdinPath := flag.String("submit", "", "Path to an archived <submitID>.stdin file")
+	binariesDirectory := flag.String("build", "", "Path to build directory the original run used")
+	runtimeBackend := flag.String("runtime", "bare", "Sandbox backend to replay under: bare, bwrap or runc")
+	configPath := flag.String("config", "", "Path to the YAML config the original run used")
+	flag.Parse()
+
+	if *stdinPath == "" || *binariesDirectory == "" {
+		fmt.Fprintln(os.Stderr, "usage: replay --submit <path>/<submitID>.stdin --build <path>")
+		os.Exit(2)
+	}
+
+	if err := run(*stdinPath, *binariesDirectory, *runtimeBackend, *configPath); err != nil {
+		fmt.Fprintln(os.Stderr, "replay failed:", err)
+		os.Exit(1)
+	}
+}
+
+func run(stdinPath, binariesDirectory, runtimeBackend, configPath string) error {
+	meta, err := submits.LoadMeta(stdinPath)
+	if err != nil {
+		return fmt.Errorf("failed to load submit meta: %w", err)
+	}
+
+	stdin, err := os.Open(stdinPath)
+	if err != nil {
+		return fmt.Errorf("failed to open archived stdin: %w", err)
+	}
+	defer stdin.Close()
+
+	cfg := config.Default()
+	if configPath != "" {
+		cfg, err = config.Load(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+	}
+	limits := cfg.LimitsForTask(meta.Task)
+
+	backend, err := runtime.New(runtimeBackend)
+	if err != nil {
+		return fmt.Errorf("failed to set up runtime backend: %w", err)
+	}
+
+	executablePath := path.Join(binariesDirectory, "ctf_"+strings.ReplaceAll(meta.Task, "-", "_"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), limits.Timeout)
+	defer cancel()
+
+	spec := runtime.Spec{
+		Executable:     executablePath,
+		Chroot:         binariesDirectory,
+		ReadOnlyRootfs: true,
+		PreCommand:     cfg.PreCommandForTask(meta.Task),
+		Limits: runtime.Limits{
+			CPUTime:      limits.CPUTime,
+			AddressSpace: limits.AddressSpace,
+			MaxPIDs:      64,
+			Network:      false,
+		},
+	}
+
+	cmd, cleanup, err := backend.BuildCommand(ctx, spec)
+	if err != nil {
+		return fmt.Errorf("failed to build sandboxed command: %w", err)
+	}
+	defer cleanup()
+	cmd.Stdin = stdin
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	started := time.Now()
+	runErr := cmd.Run()
+	wallTime := time.Since(started)
+
+	reason, exitCode := runtime.Classify(ctx, runErr)
+
+	fmt.Printf("task:             %s\n", meta.Task)
+	fmt.Printf("recorded outcome: %s (exit code %d, wall time %s)\n", meta.Outcome, meta.ExitCode, meta.WallTime)
+	fmt.Printf("replayed outcome: %s (exit code %d, wall time %s)\n", outcomeString(reason, runErr), exitCode, wallTime)
+	if meta.Flag != "" {
+		fmt.Printf("recorded flag:    %s\n", meta.Flag)
+	}
+	fmt.Println()
+
+	fmt.Println("--- stderr diff (recorded tail vs replayed) ---")
+	fmt.Print(diffLines(meta.StderrTail, stderr.String()))
+	// meta.json only ever stored a stderr tail, never the original stdout,
+	// so there's nothing recorded to diff the replayed stdout against.
+	fmt.Println("--- replayed stdout (nothing was recorded to diff it against) ---")
+	fmt.Println(stdout.String())
+
+	return nil
+}
+
+// diffLines renders a minimal line-based diff between recorded and
+// replayed, in the spirit of `diff -u`: a leading "-" marks a line only in
+// recorded, "+" only in replayed, " " a line common to both. It exists so
+// replay doesn't have to shell out to an external diff tool.
+func diffLines(recorded, replayed string) string {
+	a := strings.Split(strings.TrimRight(recorded, "\n"), "\n")
+	b := strings.Split(strings.TrimRight(replayed, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			fmt.Fprintf(&out, "  %s\n", a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			fmt.Fprintf(&out, "- %s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&out, "+ %s\n", b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		fmt.Fprintf(&out, "- %s\n", a[i])
+	}
+	for ; j < len(b); j++ {
+		fmt.Fprintf(&out, "+ %s\n", b[j])
+	}
+	return out.String()
+}
+
+func outcomeString(reason runtime.ExitReason, err error) string {
+	if reason != runtime.ExitNormal {
+		return reason.String()
+	}
+	if err == nil {
+		return "success"
+	}
+	var exitError *exec.ExitError
+	if errors.As(err, &exitError) {
+		return "failed"
+	}
+	return "error"
+}
@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestDiffLines(t *testing.T) {
+	got := diffLines("a\nb\nc", "a\nx\nc")
+	want := "  a\n- b\n+ x\n  c\n"
+	if got != want {
+		t.Fatalf("diffLines mismatch:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestDiffLinesIdentical(t *testing.T) {
+	got := diffLines("same\nstuff", "same\nstuff")
+	want := "  same\n  stuff\n"
+	if got != want {
+		t.Fatalf("diffLines of identical input should only have context lines, got %q", got)
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import "testing"
+
+func TestLimitsMergeOnlyOverridesNonZeroFields(t *testing.T) {
+	base := Limits{Timeout: 60, MaxInputSize: 1024, CPUTime: 10, AddressSpace: 2048}
+	merged := base.merge(Limits{CPUTime: 30})
+
+	if merged.CPUTime != 30 {
+		t.Fatalf("expected override CPUTime 30, got %d", merged.CPUTime)
+	}
+	if merged.Timeout != base.Timeout || merged.MaxInputSize != base.MaxInputSize || merged.AddressSpace != base.AddressSpace {
+		t.Fatalf("merge must leave zero-value fields of other untouched, got %+v", merged)
+	}
+}
+
+func TestDefaultLeavesCPUAndAddressSpaceUnlimited(t *testing.T) {
+	limits := Default().Limits
+	if limits.CPUTime != 0 || limits.AddressSpace != 0 {
+		t.Fatalf("Default must match the checker's historical behavior (no CPU/address-space limit), got %+v", limits)
+	}
+}
+
+func TestLimitsForTaskMergesAllMatchesLastWins(t *testing.T) {
+	cfg := &Config{
+		Limits: Limits{Timeout: 60},
+		Tasks: []TaskOverride{
+			{Pattern: "hard-*", Limits: Limits{CPUTime: 5}},
+			{Pattern: "hard-exploit", Limits: Limits{CPUTime: 99}},
+		},
+	}
+
+	limits := cfg.LimitsForTask("hard-exploit")
+	if limits.CPUTime != 99 {
+		t.Fatalf("expected the later matching override to win, got CPUTime=%d", limits.CPUTime)
+	}
+	if limits.Timeout != 60 {
+		t.Fatalf("expected unmatched fields to keep the global default, got Timeout=%v", limits.Timeout)
+	}
+}
+
+func TestLimitsForTaskNoOverride(t *testing.T) {
+	cfg := &Config{Limits: Limits{Timeout: 60}}
+	if limits := cfg.LimitsForTask("anything"); limits != cfg.Limits {
+		t.Fatalf("expected global limits unchanged, got %+v", limits)
+	}
+}
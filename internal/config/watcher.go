@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Watcher keeps a Config loaded from a file, swapping it atomically whenever
+// the process receives SIGHUP so that in-flight connections keep running
+// against the config they started with while new ones pick up the reload.
+type Watcher struct {
+	path    string
+	current atomic.Value // *Config
+}
+
+// NewWatcher loads path once and starts watching for SIGHUP. If path is
+// empty, it returns a Watcher serving Default() forever.
+func NewWatcher(path string) (*Watcher, error) {
+	w := &Watcher{path: path}
+
+	cfg := Default()
+	if path != "" {
+		loaded, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg = loaded
+	}
+	w.current.Store(cfg)
+
+	if path != "" {
+		go w.watch()
+	}
+	return w, nil
+}
+
+// Current returns the currently active config. Safe to call concurrently
+// with a reload.
+func (w *Watcher) Current() *Config {
+	return w.current.Load().(*Config)
+}
+
+func (w *Watcher) watch() {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	for range sighup {
+		cfg, err := Load(w.path)
+		if err != nil {
+			log.Error().Err(err).Str("path", w.path).Msg("Failed to reload config")
+			continue
+		}
+		w.current.Store(cfg)
+		log.Info().Str("path", w.path).Msg("Reloaded config")
+	}
+}
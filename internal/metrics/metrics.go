@@ -0,0 +1,79 @@
+// Package metrics holds the checker's Prometheus collectors and the
+// /metrics HTTP server that exposes them.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Checker groups every metric the checker reports. It's a plain struct of
+// already-registered collectors rather than an interface: there's only ever
+// one checker process per binary, so there is nothing to swap out.
+type Checker struct {
+	Up                 prometheus.Gauge
+	AcceptedConns      prometheus.Counter
+	AcceptErrors       prometheus.Counter
+	ConcurrentRunners  prometheus.Gauge
+	SemaphoreWait      prometheus.Histogram
+	TaskDuration       *prometheus.HistogramVec
+	TaskResults        *prometheus.CounterVec
+	FlagFetchRetries   prometheus.Counter
+	AcceptErrorsBudget prometheus.Gauge
+}
+
+// NewChecker registers and returns the checker's metrics on the default
+// Prometheus registry.
+func NewChecker() *Checker {
+	return &Checker{
+		Up: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "checker_up",
+			Help: "1 while the accept loop is running, 0 once it has given up.",
+		}),
+		AcceptedConns: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "checker_accepted_connections_total",
+			Help: "Number of TCP connections accepted.",
+		}),
+		AcceptErrors: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "checker_accept_errors_total",
+			Help: "Number of listener.Accept errors.",
+		}),
+		ConcurrentRunners: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "checker_concurrent_runners",
+			Help: "Number of submits currently holding a semaphore slot.",
+		}),
+		SemaphoreWait: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:    "checker_semaphore_wait_seconds",
+			Help:    "Time spent waiting for a free runner slot.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		TaskDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "checker_task_duration_seconds",
+			Help:    "Task execution wall time, bucketed by task name.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"task"}),
+		TaskResults: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "checker_task_results_total",
+			Help: "Per-task outcome counts.",
+		}, []string{"task", "outcome"}),
+		FlagFetchRetries: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "checker_flag_fetch_retries_total",
+			Help: "Number of retried flag-fetch attempts.",
+		}),
+		AcceptErrorsBudget: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "checker_accept_errors_budget",
+			Help: "Remaining accept-error budget before the process panics.",
+		}),
+	}
+}
+
+// Serve starts a /metrics HTTP server on address. It blocks until the
+// server stops and should be run in its own goroutine.
+func Serve(address string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return http.ListenAndServe(address, mux)
+}
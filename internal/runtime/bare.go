@@ -0,0 +1,40 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// bareBackend runs the executable directly, matching the checker's
+// historical behavior. It applies rlimits via a ulimit-wrapped shell, since
+// os/exec has no portable way to set a child's rlimits before exec, but it
+// does not chroot or bind-mount anything: callers that need real isolation
+// should pick the "bwrap" or "runc" backend instead.
+type bareBackend struct{}
+
+func (bareBackend) Name() string { return "bare" }
+
+func (bareBackend) BuildCommand(ctx context.Context, spec Spec) (*exec.Cmd, func(), error) {
+	script := shellCommand(spec, ulimitPrefix(spec.Limits))
+	return exec.CommandContext(ctx, "sh", "-c", script), func() {}, nil
+}
+
+// ulimitPrefix renders the ulimit calls os/exec has no portable API for
+// setting directly on a child before exec.
+func ulimitPrefix(limits Limits) string {
+	var b strings.Builder
+	if limits.CPUTime > 0 {
+		fmt.Fprintf(&b, "ulimit -t %d; ", limits.CPUTime)
+	}
+	if limits.AddressSpace > 0 {
+		// ulimit -v takes KiB.
+		fmt.Fprintf(&b, "ulimit -v %d; ", limits.AddressSpace/1024)
+	}
+	return b.String()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,57 @@
+package runtime
+
+import (
+	"context"
+	"os/exec"
+)
+
+// bwrapBackend runs the executable under bubblewrap, unsharing every
+// namespace by default: no network, a fresh pid namespace (Limits.MaxPIDs
+// is not enforced), and a chroot-like view of spec.Chroot mounted
+// read-only with a writable tmpfs /tmp, plus the host's library/binary
+// directories (see hostLibraryDirs) so dynamically-linked binaries can
+// still find their interpreter and shared libraries.
+type bwrapBackend struct{}
+
+func (bwrapBackend) Name() string { return "bwrap" }
+
+func (bwrapBackend) BuildCommand(ctx context.Context, spec Spec) (*exec.Cmd, func(), error) {
+	args := []string{
+		"--unshare-all",
+		"--die-with-parent",
+		"--proc", "/proc",
+		"--dev", "/dev",
+		"--tmpfs", "/tmp",
+		"--chdir", spec.Chroot,
+	}
+
+	if spec.ReadOnlyRootfs {
+		args = append(args, "--ro-bind", spec.Chroot, spec.Chroot)
+	} else {
+		args = append(args, "--bind", spec.Chroot, spec.Chroot)
+	}
+
+	// Submitted binaries are normally dynamically linked, and PreCommand
+	// needs a shell, so bind in the host's library/binary directories
+	// read-only alongside Chroot.
+	for _, dir := range hostLibraryDirs() {
+		args = append(args, "--ro-bind", dir, dir)
+	}
+
+	if spec.Limits.Network {
+		args = append(args, "--share-net")
+	}
+
+	for host, sandboxed := range spec.BindMounts {
+		args = append(args, "--ro-bind", host, sandboxed)
+	}
+
+	if ulimits := ulimitPrefix(spec.Limits); spec.PreCommand != "" || ulimits != "" {
+		args = append(args, "sh", "-c", shellCommand(spec, ulimits))
+	} else {
+		args = append(args, spec.Executable)
+		args = append(args, spec.Args...)
+	}
+
+	return exec.CommandContext(ctx, "bwrap", args...), func() {}, nil
+}
@@ -0,0 +1,39 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+)
+
+func TestBwrapBackendRunsDynamicallyLinkedBinary(t *testing.T) {
+	if _, err := exec.LookPath("bwrap"); err != nil {
+		t.Skip("bwrap not installed")
+	}
+	echoPath, err := exec.LookPath("echo")
+	if err != nil {
+		t.Skip("echo not installed")
+	}
+
+	spec := Spec{
+		Executable: echoPath,
+		Args:       []string{"hello"},
+		Chroot:     t.TempDir(),
+	}
+
+	cmd, cleanup, err := (bwrapBackend{}).BuildCommand(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("BuildCommand: %v", err)
+	}
+	defer cleanup()
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("bwrap run failed: %v (stdout: %q)", err, out.String())
+	}
+	if got := out.String(); got != "hello\n" {
+		t.Fatalf("expected %q, got %q; the sandbox likely can't find echo's shared libraries", "hello\n", got)
+	}
+}
@@ -0,0 +1,66 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// Classify inspects the error returned by (*exec.Cmd).Wait, together with
+// the context the command ran under, and decides why the process stopped.
+// Everything that isn't a timeout, an OOM kill or a self-inflicted signal is
+// ExitNormal, including a crash signal like SIGSEGV/SIGABRT/SIGBUS/SIGFPE:
+// that, like a plain non-zero exit code, is the expected outcome of a
+// successfully exploited binary, and the only case in which the checker
+// should go on to fetch a flag.
+func Classify(ctx context.Context, err error) (ExitReason, int) {
+	if err == nil {
+		return ExitNormal, 0
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return ExitTimeout, -1
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return ExitNormal, -1
+	}
+
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return ExitNormal, exitErr.ExitCode()
+	}
+
+	switch status.Signal() {
+	case syscall.SIGKILL:
+		if ctx.Err() == context.Canceled {
+			// cmd.WaitDelay force-killed a run that was still going when a
+			// shutdown drain deadline cancelled its context; that's us
+			// killing our own child, not the sandbox's OOM killer.
+			return ExitSignaled, -1
+		}
+		// Otherwise the sandbox (cgroup OOM killer or RLIMIT_AS) has no
+		// graceful way to report "out of memory" other than a bare SIGKILL,
+		// so treat any other unexplained SIGKILL as an OOM kill.
+		return ExitOOM, -1
+	case syscall.SIGINT:
+		// The proxy sends this to the child itself once the client
+		// disconnects (see commandProxy.handleStdin); it isn't a crash.
+		return ExitSignaled, -1
+	case syscall.SIGTERM:
+		if ctx.Err() == context.Canceled {
+			// cmd.Cancel sends SIGTERM to ask the child to exit cleanly
+			// before a shutdown drain deadline or task timeout resorts to
+			// SIGKILL (see doHandleConnection); that's us tearing the run
+			// down, not the submitted binary crashing on its own.
+			return ExitSignaled, -1
+		}
+		return ExitNormal, exitErr.ExitCode()
+	default:
+		// A genuine crash signal from the submitted binary: fall through to
+		// the normal non-zero-exit/flag-fetch path, same as a plain exit code.
+		return ExitNormal, exitErr.ExitCode()
+	}
+}
@@ -0,0 +1,100 @@
+package runtime
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// runSignaled runs a short-lived shell under ctx and sends it sig, returning
+// the *exec.ExitError Wait reports so tests can feed it to Classify.
+func runSignaled(t *testing.T, ctx context.Context, sig string) error {
+	t.Helper()
+	cmd := exec.CommandContext(ctx, "sh", "-c", "kill -"+sig+" $$; sleep 5")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start test command: %v", err)
+	}
+	return cmd.Wait()
+}
+
+func TestClassifyCrashSignalFetchesFlag(t *testing.T) {
+	ctx := context.Background()
+	err := runSignaled(t, ctx, "SEGV")
+
+	reason, _ := Classify(ctx, err)
+	if reason != ExitNormal {
+		t.Fatalf("crash signal should classify as ExitNormal so the checker still fetches a flag, got %s", reason)
+	}
+}
+
+func TestClassifySelfInflictedSIGINT(t *testing.T) {
+	ctx := context.Background()
+	err := runSignaled(t, ctx, "INT")
+
+	reason, _ := Classify(ctx, err)
+	if reason != ExitSignaled {
+		t.Fatalf("expected ExitSignaled for SIGINT, got %s", reason)
+	}
+}
+
+func TestClassifyOOMKill(t *testing.T) {
+	ctx := context.Background()
+	err := runSignaled(t, ctx, "KILL")
+
+	reason, _ := Classify(ctx, err)
+	if reason != ExitOOM {
+		t.Fatalf("expected ExitOOM for an unexplained SIGKILL, got %s", reason)
+	}
+}
+
+func TestClassifyShutdownForcedKillIsNotOOM(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel := context.WithTimeout(parent, time.Minute)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", "trap '' TERM; sleep 5")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start test command: %v", err)
+	}
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = 200 * time.Millisecond
+
+	cancelParent()
+	err := cmd.Wait()
+
+	reason, _ := Classify(ctx, err)
+	if reason != ExitSignaled {
+		t.Fatalf("a shutdown-driven force-kill must not be classified as OOM, got %s", reason)
+	}
+}
+
+func TestClassifyTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sleep", "5")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start test command: %v", err)
+	}
+	err := cmd.Wait()
+
+	reason, _ := Classify(ctx, err)
+	if reason != ExitTimeout {
+		t.Fatalf("expected ExitTimeout, got %s", reason)
+	}
+}
+
+func TestClassifyNormalExit(t *testing.T) {
+	ctx := context.Background()
+	cmd := exec.CommandContext(ctx, "sh", "-c", "exit 7")
+	err := cmd.Run()
+
+	reason, code := Classify(ctx, err)
+	if reason != ExitNormal || code != 7 {
+		t.Fatalf("expected ExitNormal/7, got %s/%d", reason, code)
+	}
+}
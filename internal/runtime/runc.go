@@ -0,0 +1,131 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// runcBackend runs the executable inside a minimal OCI bundle via runc,
+// giving the strongest isolation of the three backends at the cost of
+// needing runc installed and (usually) root or rootless-runc support on the
+// host. Each run gets its own throwaway bundle directory under os.TempDir;
+// the cleanup func BuildCommand returns deletes the container and bundle
+// once the caller is done with it.
+type runcBackend struct{}
+
+func (runcBackend) Name() string { return "runc" }
+
+func (r runcBackend) BuildCommand(ctx context.Context, spec Spec) (*exec.Cmd, func(), error) {
+	bundle, err := os.MkdirTemp("", "notmanytask-runc-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create bundle dir: %w", err)
+	}
+
+	config := r.bundleConfig(spec)
+	buf, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		os.RemoveAll(bundle)
+		return nil, nil, fmt.Errorf("failed to marshal bundle config: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(bundle, "config.json"), buf, 0644); err != nil {
+		os.RemoveAll(bundle)
+		return nil, nil, fmt.Errorf("failed to write bundle config: %w", err)
+	}
+
+	containerID := filepath.Base(bundle)
+	cmd := exec.CommandContext(ctx, "runc", "run", "--bundle", bundle, containerID)
+
+	cleanup := func() {
+		// "runc run" already cleans up the container's runtime state on
+		// exit, but "delete --force" is cheap insurance against it being
+		// killed before it gets there; RemoveAll drops the bundle dir
+		// either way so repeated submits don't leak them.
+		exec.Command("runc", "delete", "--force", containerID).Run()
+		os.RemoveAll(bundle)
+	}
+	return cmd, cleanup, nil
+}
+
+// bundleConfig builds a minimal OCI runtime-spec document. It intentionally
+// covers only the fields the checker cares about (mounts, rlimits, network
+// namespace); a real deployment should still review it before trusting it
+// with untrusted binaries.
+func (r runcBackend) bundleConfig(spec Spec) map[string]interface{} {
+	namespaces := []map[string]string{
+		{"type": "pid"},
+		{"type": "mount"},
+		{"type": "ipc"},
+		{"type": "uts"},
+	}
+	if !spec.Limits.Network {
+		namespaces = append(namespaces, map[string]string{"type": "network"})
+	}
+
+	mounts := []map[string]interface{}{
+		{"destination": "/tmp", "type": "tmpfs", "source": "tmpfs"},
+	}
+	// Submitted binaries are normally dynamically linked, and PreCommand
+	// needs a shell, so bind in the host's library/binary directories
+	// read-only alongside spec.Chroot.
+	for _, dir := range hostLibraryDirs() {
+		mounts = append(mounts, map[string]interface{}{
+			"destination": dir,
+			"type":        "bind",
+			"source":      dir,
+			"options":     []string{"ro", "bind"},
+		})
+	}
+	for host, sandboxed := range spec.BindMounts {
+		mounts = append(mounts, map[string]interface{}{
+			"destination": sandboxed,
+			"type":        "bind",
+			"source":      host,
+			"options":     []string{"ro", "bind"},
+		})
+	}
+
+	var rlimits []map[string]interface{}
+	if spec.Limits.CPUTime > 0 {
+		rlimits = append(rlimits, map[string]interface{}{
+			"type": "RLIMIT_CPU",
+			"hard": uint64(spec.Limits.CPUTime),
+			"soft": uint64(spec.Limits.CPUTime),
+		})
+	}
+	if spec.Limits.AddressSpace > 0 {
+		rlimits = append(rlimits, map[string]interface{}{
+			"type": "RLIMIT_AS",
+			"hard": uint64(spec.Limits.AddressSpace),
+			"soft": uint64(spec.Limits.AddressSpace),
+		})
+	}
+
+	processArgs := append([]string{spec.Executable}, spec.Args...)
+	if spec.PreCommand != "" {
+		processArgs = []string{"sh", "-c", shellCommand(spec, "")}
+	}
+
+	return map[string]interface{}{
+		"ociVersion": "1.0.2",
+		"root": map[string]interface{}{
+			"path":     spec.Chroot,
+			"readonly": spec.ReadOnlyRootfs,
+		},
+		"process": map[string]interface{}{
+			"args":    processArgs,
+			"cwd":     "/",
+			"rlimits": rlimits,
+		},
+		"linux": map[string]interface{}{
+			"namespaces": namespaces,
+			"resources": map[string]interface{}{
+				"pids": map[string]interface{}{"limit": spec.Limits.MaxPIDs},
+			},
+		},
+		"mounts": mounts,
+	}
+}
@@ -0,0 +1,63 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestRuncBackendRunsDynamicallyLinkedBinary(t *testing.T) {
+	if _, err := exec.LookPath("runc"); err != nil {
+		t.Skip("runc not installed")
+	}
+	echoPath, err := exec.LookPath("echo")
+	if err != nil {
+		t.Skip("echo not installed")
+	}
+
+	spec := Spec{
+		Executable: echoPath,
+		Args:       []string{"hello"},
+		Chroot:     t.TempDir(),
+	}
+
+	backend := runcBackend{}
+	cmd, cleanup, err := backend.BuildCommand(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("BuildCommand: %v", err)
+	}
+	defer cleanup()
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("runc run failed: %v (output: %q)", err, out.String())
+	}
+	if got := out.String(); got != "hello\n" {
+		t.Fatalf("expected %q, got %q; the sandbox likely can't find echo's shared libraries", "hello\n", got)
+	}
+}
+
+func TestRuncBackendCleansUpBundleDir(t *testing.T) {
+	if _, err := exec.LookPath("runc"); err != nil {
+		t.Skip("runc not installed")
+	}
+
+	spec := Spec{Executable: "/bin/true", Chroot: t.TempDir()}
+	backend := runcBackend{}
+	cmd, cleanup, err := backend.BuildCommand(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("BuildCommand: %v", err)
+	}
+	bundle := cmd.Args[len(cmd.Args)-2]
+
+	cmd.Run()
+	cleanup()
+
+	if _, err := os.Stat(bundle); !os.IsNotExist(err) {
+		t.Fatalf("expected bundle dir %q to be removed after cleanup, stat err: %v", bundle, err)
+	}
+}
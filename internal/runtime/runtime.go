@@ -0,0 +1,141 @@
+// Package runtime wraps execution of submitted binaries in a per-submit
+// sandbox. It is intentionally modelled after the OCI runtime-shim split:
+// a Spec describes what should run and under which constraints, and a
+// Backend turns that Spec into an *exec.Cmd using whatever isolation
+// primitives it supports.
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Limits bounds the resources a sandboxed process may consume.
+type Limits struct {
+	// CPUTime is enforced via RLIMIT_CPU (process CPU time, not wall clock).
+	CPUTime int // seconds, 0 means unlimited
+	// AddressSpace is enforced via RLIMIT_AS, in bytes. 0 means unlimited.
+	AddressSpace int64
+	// MaxPIDs caps the number of processes/threads the sandbox may fork.
+	// Only the runc backend enforces it (through the pids cgroup); bare and
+	// bwrap ignore it.
+	MaxPIDs int64
+	// Network allows outbound network access. Defaults to false: the bwrap
+	// and runc backends then give the binary no network access. The bare
+	// backend does no network isolation and ignores this field.
+	Network bool
+}
+
+// Spec describes a single sandboxed run of a submitted binary.
+type Spec struct {
+	// Executable is the absolute path to the checker binary to run.
+	Executable string
+	// Args are passed to Executable unchanged.
+	Args []string
+	// Chroot is the directory the sandbox should be confined to, typically
+	// the checker's binariesDirectory.
+	Chroot string
+	// BindMounts maps host paths (e.g. the submit file) to paths inside the
+	// sandbox. Backends that cannot bind-mount may ignore this.
+	BindMounts map[string]string
+	// ReadOnlyRootfs mounts Chroot read-only, with a writable tmpfs at /tmp.
+	ReadOnlyRootfs bool
+	// PreCommand, if set, is run in a shell before Executable and must
+	// succeed for Executable to start at all (e.g. unpacking task fixtures).
+	PreCommand string
+	Limits     Limits
+}
+
+// shellCommand renders "precommand && exec target args..." as a single sh
+// -c script, quoting target/args but leaving PreCommand as the operator
+// wrote it in the config.
+func shellCommand(spec Spec, ulimits string) string {
+	var b strings.Builder
+	b.WriteString(ulimits)
+	if spec.PreCommand != "" {
+		b.WriteString(spec.PreCommand)
+		b.WriteString(" && ")
+	}
+	b.WriteString("exec ")
+	b.WriteString(shellQuote(spec.Executable))
+	for _, arg := range spec.Args {
+		b.WriteByte(' ')
+		b.WriteString(shellQuote(arg))
+	}
+	return b.String()
+}
+
+// ExitReason classifies why a sandboxed process stopped, so that callers can
+// tell an OOM kill or a sandbox-enforced timeout apart from a task binary
+// that legitimately exited with a non-zero status.
+type ExitReason int
+
+const (
+	ExitNormal ExitReason = iota
+	ExitTimeout
+	ExitOOM
+	ExitSignaled
+)
+
+func (r ExitReason) String() string {
+	switch r {
+	case ExitNormal:
+		return "normal"
+	case ExitTimeout:
+		return "timeout"
+	case ExitOOM:
+		return "oom"
+	case ExitSignaled:
+		return "signaled"
+	default:
+		return "unknown"
+	}
+}
+
+// Backend turns a Spec into a runnable *exec.Cmd. Backends do not start or
+// wait on the command; callers proxy its stdio themselves. The returned
+// cleanup func releases any resources the backend allocated (e.g. a runc
+// bundle directory) and must be called once the command has exited,
+// regardless of its outcome.
+type Backend interface {
+	Name() string
+	BuildCommand(ctx context.Context, spec Spec) (*exec.Cmd, func(), error)
+}
+
+// hostLibraryDirs lists the host directories a dynamically-linked
+// submitted binary (the normal case for compiled C/C++ CTF tasks) needs on
+// its library search path, to find its ELF interpreter, and to find a shell
+// for PreCommand. Backends that give the sandbox a filesystem view of only
+// spec.Chroot must bind these in too, or exec fails with a missing
+// interpreter/shared-library error. Only paths that exist on the host are
+// returned.
+func hostLibraryDirs() []string {
+	candidates := []string{"/lib", "/lib64", "/usr/lib", "/usr/lib64", "/bin", "/usr/bin", "/sbin", "/usr/sbin"}
+	var dirs []string
+	for _, dir := range candidates {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// New looks up a backend by name. "bare" is always available and matches
+// the checker's historical behavior (no real isolation, best-effort
+// rlimits). "runc" and "bwrap" shell out to the respective tools to provide
+// real filesystem/network/pid isolation.
+func New(name string) (Backend, error) {
+	switch name {
+	case "", "bare":
+		return bareBackend{}, nil
+	case "bwrap":
+		return bwrapBackend{}, nil
+	case "runc":
+		return runcBackend{}, nil
+	default:
+		return nil, fmt.Errorf("unknown runtime backend %q", name)
+	}
+}
@@ -2,8 +2,10 @@ package scorer
 
 import (
 	"fmt"
+	"math"
 	"time"
 
+	"github.com/bigredeye/notmanytask/internal/config"
 	"github.com/bigredeye/notmanytask/internal/database"
 	"github.com/bigredeye/notmanytask/internal/deadlines"
 	"github.com/bigredeye/notmanytask/internal/models"
@@ -13,10 +15,11 @@ import (
 type Scorer struct {
 	deadlines *deadlines.Fetcher
 	db        *database.DataBase
+	config    *config.Watcher
 }
 
-func NewScorer(db *database.DataBase, deadlines *deadlines.Fetcher) *Scorer {
-	return &Scorer{deadlines, db}
+func NewScorer(db *database.DataBase, deadlines *deadlines.Fetcher, cfg *config.Watcher) *Scorer {
+	return &Scorer{deadlines, db, cfg}
 }
 
 func pipelineLess(left *models.Pipeline, right *models.Pipeline) bool {
@@ -98,25 +101,50 @@ const (
 	week = time.Hour * 24 * 7
 )
 
-// TODO(BigRedEye): Do not hardcode scoring logic
-// Maybe read scoring model from deadlines?
+// scorePipeline turns a successful pipeline into a partial score according
+// to the scoring policy configured for task (see config.ScoringPolicy).
 func (s Scorer) scorePipeline(task *deadlines.Task, group *deadlines.TaskGroup, pipeline *models.Pipeline) int {
 	if pipeline.Status != models.PipelineStatusSuccess {
 		return 0
 	}
 
 	deadline := group.Deadline.Time
-
 	if pipeline.CreatedAt.Before(deadline) {
 		return task.Score
 	}
 
-	weekAfter := group.Deadline.Time.Add(week)
-	if pipeline.CreatedAt.After(weekAfter) {
-		return task.Score / 2
+	policy := s.config.Current().ScoringForTask(task.Task)
+	mult := lateMultiplier(policy, pipeline.CreatedAt.Sub(deadline))
+	if groupMult, ok := policy.GroupMultipliers[group.Group]; ok {
+		mult *= groupMult
 	}
 
-	mult := 0.5 + 0.5*pipeline.CreatedAt.Sub(deadline).Seconds()/(weekAfter.Sub(deadline)).Seconds()
-
 	return int(float64(task.Score) * mult)
+}
+
+// lateMultiplier returns the fraction of task.Score awarded for a submit
+// made late by the given duration, per policy.Kind:
+//   - "step": a flat 0.5 as soon as the deadline has passed.
+//   - "exponential": decays as 0.5^(late/HalfLife) towards 0, HalfLife
+//     defaulting to one week.
+//   - anything else (including "linear", the historical default): 0.5 right
+//     at the deadline, rising linearly to the full score by one week after
+//     the deadline, then dropping to a flat 0.5 (matches the checker's
+//     original hardcoded formula).
+func lateMultiplier(policy config.ScoringPolicy, late time.Duration) float64 {
+	switch policy.Kind {
+	case "step":
+		return 0.5
+	case "exponential":
+		halfLife := policy.HalfLife
+		if halfLife <= 0 {
+			halfLife = week
+		}
+		return math.Pow(0.5, late.Seconds()/halfLife.Seconds())
+	default:
+		if late >= week {
+			return 0.5
+		}
+		return 0.5 + 0.5*late.Seconds()/week.Seconds()
+	}
 }
\ No newline at end of file
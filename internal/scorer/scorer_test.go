@@ -0,0 +1,47 @@
+package scorer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bigredeye/notmanytask/internal/config"
+)
+
+func TestLateMultiplierStep(t *testing.T) {
+	policy := config.ScoringPolicy{Kind: "step"}
+	for _, late := range []time.Duration{0, time.Hour, 2 * week} {
+		if mult := lateMultiplier(policy, late); mult != 0.5 {
+			t.Fatalf("step policy should always return 0.5, got %v for late=%v", mult, late)
+		}
+	}
+}
+
+func TestLateMultiplierExponentialHalfLife(t *testing.T) {
+	policy := config.ScoringPolicy{Kind: "exponential", HalfLife: time.Hour}
+	if mult := lateMultiplier(policy, time.Hour); mult != 0.5 {
+		t.Fatalf("expected 0.5 after exactly one half-life, got %v", mult)
+	}
+	if mult := lateMultiplier(policy, 2*time.Hour); mult != 0.25 {
+		t.Fatalf("expected 0.25 after two half-lives, got %v", mult)
+	}
+}
+
+func TestLateMultiplierExponentialDefaultHalfLife(t *testing.T) {
+	policy := config.ScoringPolicy{Kind: "exponential"}
+	if mult := lateMultiplier(policy, week); mult != 0.5 {
+		t.Fatalf("expected default half-life of one week, got %v", mult)
+	}
+}
+
+func TestLateMultiplierDefaultLinear(t *testing.T) {
+	policy := config.ScoringPolicy{}
+	if mult := lateMultiplier(policy, 0); mult != 0.5 {
+		t.Fatalf("expected 0.5 right at the deadline, got %v", mult)
+	}
+	if mult := lateMultiplier(policy, week); mult != 0.5 {
+		t.Fatalf("expected the curve to drop back to 0.5 at exactly one week, got %v", mult)
+	}
+	if mult := lateMultiplier(policy, week/2); mult <= 0.5 || mult >= 1.0 {
+		t.Fatalf("expected a mid-week late submit to fall strictly between 0.5 and 1.0, got %v", mult)
+	}
+}
@@ -0,0 +1,149 @@
+// Package submits implements a content-addressed archive of everything
+// submitted to the checker, so that a past run can be replayed later (see
+// cmd/replay).
+package submits
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Meta is the sidecar JSON stored next to every archived submit.
+type Meta struct {
+	Task           string        `json:"task"`
+	ConnID         int           `json:"conn_id"`
+	Digest         string        `json:"digest"`
+	Outcome        string        `json:"outcome"`
+	ExitCode       int           `json:"exit_code"`
+	WallTime       time.Duration `json:"wall_time"`
+	StderrTail     string        `json:"stderr_tail"`
+	Flag           string        `json:"flag,omitempty"`
+	RemoteAddrHash string        `json:"remote_addr_hash"`
+	ConfigHash     string        `json:"config_hash"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// Archive stores submits under root as <sha256>/<task>/<submitID>.stdin, with
+// a <submitID>.meta.json sidecar next to each one. submitID is derived from
+// the scratch file os.CreateTemp hands out, so it stays unique across
+// process restarts, unlike the per-connection ConnID recorded in Meta.
+type Archive struct {
+	root string
+}
+
+// NewArchive opens (creating if needed) a content-addressed archive at root.
+func NewArchive(root string) (*Archive, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to mkdir submits archive: %w", err)
+	}
+	return &Archive{root: root}, nil
+}
+
+// Pending is an in-progress submit: its stdin is being written to a scratch
+// file while the checker still doesn't know the task's outcome.
+type Pending struct {
+	archive *Archive
+	tmpFile *os.File
+	id      string
+	hash    hash.Hash
+	writer  io.Writer
+}
+
+// Begin starts archiving a new submit. Callers should write the submit's
+// stdin to Writer() as it streams in, then call Finish once the task has
+// run to completion.
+func (a *Archive) Begin() (*Pending, error) {
+	tmp, err := os.CreateTemp(a.root, "submit-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scratch submit file: %w", err)
+	}
+
+	// os.CreateTemp's random suffix is already unique across restarts,
+	// unlike an in-process connection counter, so reuse it as the submit's
+	// archive ID instead of minting a new one.
+	id := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(tmp.Name()), "submit-"), ".tmp")
+
+	h := sha256.New()
+	return &Pending{
+		archive: a,
+		tmpFile: tmp,
+		id:      id,
+		hash:    h,
+		writer:  io.MultiWriter(tmp, h),
+	}, nil
+}
+
+// Writer returns the io.Writer the submit's stdin should be teed into.
+func (p *Pending) Writer() io.Writer {
+	return p.writer
+}
+
+// Path returns the scratch file's current path, useful for bind-mounting
+// the in-progress submit into a sandbox while it still streams in.
+func (p *Pending) Path() string {
+	return p.tmpFile.Name()
+}
+
+// Finish moves the scratch file into its content-addressed location and
+// writes meta alongside it, filling in meta.Digest. It always closes and
+// removes the scratch file, even on error.
+func (p *Pending) Finish(meta Meta) (string, error) {
+	defer os.Remove(p.tmpFile.Name())
+	if err := p.tmpFile.Close(); err != nil {
+		return "", fmt.Errorf("failed to close scratch submit file: %w", err)
+	}
+
+	digest := hex.EncodeToString(p.hash.Sum(nil))
+	meta.Digest = digest
+
+	dir := filepath.Join(p.archive.root, digest, meta.Task)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to mkdir archive entry: %w", err)
+	}
+
+	dest := filepath.Join(dir, p.id+".stdin")
+	if err := os.Rename(p.tmpFile.Name(), dest); err != nil {
+		return "", fmt.Errorf("failed to archive submit: %w", err)
+	}
+
+	buf, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal submit meta: %w", err)
+	}
+	metaPath := filepath.Join(dir, p.id+".meta.json")
+	if err := os.WriteFile(metaPath, buf, 0644); err != nil {
+		return "", fmt.Errorf("failed to write submit meta: %w", err)
+	}
+
+	return dest, nil
+}
+
+// HashString sha256-hashes s, for fields (like a remote address) that
+// shouldn't be stored in the clear in meta.json.
+func HashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadMeta reads the meta.json sidecar next to a stored .stdin file.
+func LoadMeta(stdinPath string) (*Meta, error) {
+	metaPath := stdinPath[:len(stdinPath)-len(".stdin")] + ".meta.json"
+	buf, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read submit meta: %w", err)
+	}
+
+	var meta Meta
+	if err := json.Unmarshal(buf, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse submit meta: %w", err)
+	}
+	return &meta, nil
+}
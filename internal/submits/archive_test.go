@@ -0,0 +1,49 @@
+package submits
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestArchiveDoesNotCollideAcrossSeparateProcesses simulates two submits
+// with the same content, task and ConnID coming from separate "restarts" of
+// the checker (i.e. two distinct Archive instances, each starting its own
+// connection counter back at 0). They must not clobber each other's files.
+func TestArchiveDoesNotCollideAcrossSeparateProcesses(t *testing.T) {
+	root := t.TempDir()
+
+	var dests []string
+	for i := 0; i < 2; i++ {
+		archive, err := NewArchive(root)
+		if err != nil {
+			t.Fatalf("NewArchive: %v", err)
+		}
+
+		pending, err := archive.Begin()
+		if err != nil {
+			t.Fatalf("Begin: %v", err)
+		}
+		if _, err := pending.Writer().Write([]byte("same stdin")); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+
+		dest, err := pending.Finish(Meta{Task: "hard-task", ConnID: 0})
+		if err != nil {
+			t.Fatalf("Finish: %v", err)
+		}
+		dests = append(dests, dest)
+	}
+
+	if dests[0] == dests[1] {
+		t.Fatalf("two submits from separate processes landed on the same archive path %q", dests[0])
+	}
+	for _, dest := range dests {
+		if _, err := os.Stat(dest); err != nil {
+			t.Fatalf("expected archived file %q to exist: %v", dest, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Dir(dests[0])); err != nil {
+		t.Fatalf("expected shared task dir to exist: %v", err)
+	}
+}